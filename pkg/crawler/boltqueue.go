@@ -0,0 +1,189 @@
+package crawler
+
+import (
+	"encoding/json"
+
+	"go.etcd.io/bbolt"
+)
+
+var frontierBucket = []byte("frontier")
+
+// BoltQueue is a Queue implementation backed by a BoltDB file, so a
+// recursive crawl's (url, depth, status) state survives a restart and
+// can be picked back up with --resume.
+type BoltQueue struct {
+	db *bbolt.DB
+}
+
+// NewBoltQueue opens (creating if needed) a BoltDB file at path to back
+// a persistent frontier.
+func NewBoltQueue(path string) (*BoltQueue, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(frontierBucket)
+		if err != nil {
+			return err
+		}
+		return resetInProgress(bucket)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltQueue{db: db}, nil
+}
+
+// resetInProgress flips any item left in-progress back to pending. It
+// runs once when a BoltQueue is opened, so a URL that was handed out by
+// Pop but never reached MarkDone (the process crashed, or was ctrl-c'd,
+// mid-fetch) is retried on --resume instead of being silently dropped.
+func resetInProgress(bucket *bbolt.Bucket) error {
+	cursor := bucket.Cursor()
+
+	for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+		var item QueueItem
+		if err := json.Unmarshal(value, &item); err != nil {
+			continue
+		}
+		if item.Status != QueueStatusInProgress {
+			continue
+		}
+
+		item.Status = QueueStatusPending
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(key, encoded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Push implements Queue.
+func (q *BoltQueue) Push(item QueueItem) bool {
+	pushed := false
+
+	q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(frontierBucket)
+		if bucket.Get([]byte(item.URL)) != nil {
+			return nil
+		}
+
+		item.Status = QueueStatusPending
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		pushed = true
+		return bucket.Put([]byte(item.URL), encoded)
+	})
+
+	return pushed
+}
+
+// Pop implements Queue. It scans for the first pending item and marks
+// it in-progress (not done — that only happens once MarkDone is
+// called with the real result); BoltQueue is meant for politely-paced
+// recursive crawls, not high-throughput queueing, so a linear scan is
+// an acceptable trade-off for the simplicity of storing everything in
+// one bucket.
+func (q *BoltQueue) Pop() (item QueueItem, ok bool) {
+	q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(frontierBucket)
+		cursor := bucket.Cursor()
+
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			var candidate QueueItem
+			if err := json.Unmarshal(value, &candidate); err != nil {
+				continue
+			}
+			if candidate.Status != QueueStatusPending {
+				continue
+			}
+
+			candidate.Status = QueueStatusInProgress
+			encoded, err := json.Marshal(candidate)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, encoded); err != nil {
+				return err
+			}
+
+			candidate.Status = QueueStatusPending
+			item, ok = candidate, true
+			return nil
+		}
+
+		return nil
+	})
+
+	return item, ok
+}
+
+// MarkDone implements Queue. It's the only place that flips an item to
+// done — Pop only marks it in-progress — so a crash between the two
+// leaves the item in-progress and resetInProgress retries it on the
+// next open.
+func (q *BoltQueue) MarkDone(url string) error {
+	return q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(frontierBucket)
+
+		raw := bucket.Get([]byte(url))
+		if raw == nil {
+			return nil
+		}
+
+		var item QueueItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+
+		item.Status = QueueStatusDone
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(url), encoded)
+	})
+}
+
+// Len implements Queue. It scans every record to count pending ones,
+// the same trade-off Pop makes for the sake of a single bucket.
+func (q *BoltQueue) Len() int {
+	count := 0
+
+	q.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(frontierBucket)
+		cursor := bucket.Cursor()
+
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			var item QueueItem
+			if err := json.Unmarshal(value, &item); err != nil {
+				continue
+			}
+			if item.Status == QueueStatusPending {
+				count++
+			}
+		}
+
+		return nil
+	})
+
+	return count
+}
+
+// Close implements Queue.
+func (q *BoltQueue) Close() error {
+	return q.db.Close()
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltQueuePopDoesNotMarkDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.db")
+
+	queue, err := NewBoltQueue(path)
+	if err != nil {
+		t.Fatalf("NewBoltQueue: %v", err)
+	}
+
+	queue.Push(QueueItem{URL: "https://example.com/a", Depth: 0})
+
+	item, ok := queue.Pop()
+	if !ok || item.URL != "https://example.com/a" {
+		t.Fatalf("Pop() = %+v, %v, want the pushed item", item, ok)
+	}
+
+	if _, ok := queue.Pop(); ok {
+		t.Fatalf("Pop() returned the same item twice before MarkDone")
+	}
+
+	if err := queue.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Simulate a crash between Pop and MarkDone: reopening the same
+	// file must retry the URL instead of treating it as done forever.
+	reopened, err := NewBoltQueue(path)
+	if err != nil {
+		t.Fatalf("reopen NewBoltQueue: %v", err)
+	}
+	defer reopened.Close()
+
+	retried, ok := reopened.Pop()
+	if !ok || retried.URL != "https://example.com/a" {
+		t.Fatalf("Pop() after reopen = %+v, %v, want the URL to be retried", retried, ok)
+	}
+
+	if err := reopened.MarkDone(retried.URL); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+
+	if _, ok := reopened.Pop(); ok {
+		t.Fatalf("Pop() returned a URL that was already MarkDone")
+	}
+}
+
+func TestBoltQueuePushDeduplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.db")
+
+	queue, err := NewBoltQueue(path)
+	if err != nil {
+		t.Fatalf("NewBoltQueue: %v", err)
+	}
+	defer queue.Close()
+
+	if !queue.Push(QueueItem{URL: "https://example.com/a"}) {
+		t.Fatalf("first Push() = false, want true")
+	}
+	if queue.Push(QueueItem{URL: "https://example.com/a"}) {
+		t.Fatalf("second Push() of the same URL = true, want false")
+	}
+}
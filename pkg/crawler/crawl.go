@@ -7,6 +7,8 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/yterajima/go-sitemap"
+
+	"github.com/talkhot/crowlet/pkg/metrics"
 )
 
 // CrawlResult is the result from a single crawling
@@ -25,6 +27,9 @@ type CrawlStats struct {
 	Average200Time time.Duration
 	Max200Time     time.Duration
 	Non200Urls     []CrawlResult
+	// RaceAnomalies holds one entry per URL crawled under RaceMode
+	// whose simultaneous attempts returned diverging status codes.
+	RaceAnomalies []CrawlResult
 }
 
 // CrawlConfig holds crawling configuration.
@@ -34,6 +39,32 @@ type CrawlConfig struct {
 	HTTP       HTTPConfig
 	Links      CrawlPageLinksConfig
 	HTTPGetter ConcurrentHTTPGetter
+	WARC       WARCConfig
+
+	// MaxDepth enables recursive crawling when greater than 0: links
+	// discovered on a page are themselves crawled, up to this many hops
+	// from the seed URLs.
+	MaxDepth int
+	// Scope restricts which discovered links are added to the frontier
+	// during a recursive crawl.
+	Scope ScopePolicy
+	// Queue backs the frontier for a recursive crawl. Defaults to a
+	// MemoryQueue when nil.
+	Queue Queue
+	// Resume skips seeding the queue with urls, so a crawl picks back up
+	// from whatever is already pending in a persistent Queue.
+	Resume bool
+
+	// Seeds augments AsyncCrawl's URL list with whatever each provider
+	// discovers, e.g. historical URLs from the Wayback Machine or
+	// Common Crawl.
+	Seeds []SeedProvider
+
+	// MetricsAddr, if set, starts a Prometheus /metrics endpoint on this
+	// address when the crawl begins, so it can be scraped by a
+	// monitoring system when crowlet is run as a scheduled synthetic
+	// monitor. The server is not shut down when AsyncCrawl returns.
+	MetricsAddr string
 }
 
 // CrawlPageLinksConfig holds the crawling policy for links
@@ -74,6 +105,9 @@ func MergeCrawlStats(statsA, statsB CrawlStats) (stats CrawlStats) {
 	stats.Non200Urls = append(stats.Non200Urls, statsA.Non200Urls...)
 	stats.Non200Urls = append(stats.Non200Urls, statsB.Non200Urls...)
 
+	stats.RaceAnomalies = append(stats.RaceAnomalies, statsA.RaceAnomalies...)
+	stats.RaceAnomalies = append(stats.RaceAnomalies, statsB.RaceAnomalies...)
+
 	return
 }
 
@@ -113,6 +147,51 @@ func GetSitemapUrlsAsStrings(sitemapURL string) (urls []string, err error) {
 	return
 }
 
+// discoverSitemapSeeds fetches robots.txt for each distinct host among
+// urls and returns the URLs listed in any Sitemap: directives it
+// declares, so they can be merged into AsyncCrawl's seed list. It reuses
+// the crawl-scoped robots.Cache from httpConfig.network rather than
+// building its own, so a host's robots.txt is fetched once per crawl
+// instead of once here and again from RunConcurrentGet.
+func discoverSitemapSeeds(urls []string, httpConfig HTTPConfig) (discovered []string) {
+	cache := httpConfig.network.robots
+
+	seenHosts := make(map[string]struct{})
+	seenUrls := make(map[string]struct{})
+	for _, seed := range urls {
+		seenUrls[seed] = struct{}{}
+	}
+
+	for _, seed := range urls {
+		parsed, err := url.Parse(seed)
+		if err != nil {
+			continue
+		}
+		if _, ok := seenHosts[parsed.Host]; ok {
+			continue
+		}
+		seenHosts[parsed.Host] = struct{}{}
+
+		for _, sitemapURL := range cache.Sitemaps(seed) {
+			sitemapUrls, err := GetSitemapUrlsAsStrings(sitemapURL)
+			if err != nil {
+				log.Error("error reading sitemap discovered via robots.txt: ", err)
+				continue
+			}
+
+			for _, sitemapUrl := range sitemapUrls {
+				if _, ok := seenUrls[sitemapUrl]; ok {
+					continue
+				}
+				seenUrls[sitemapUrl] = struct{}{}
+				discovered = append(discovered, sitemapUrl)
+			}
+		}
+	}
+
+	return
+}
+
 // AsyncCrawl crawls asynchronously URLs from a sitemap and prints related
 // information. Throttle is the maximum number of parallel HTTP requests.
 // Host overrides the hostname used in the sitemap if provided,
@@ -126,14 +205,55 @@ func AsyncCrawl(urls []string, config CrawlConfig, quit <-chan struct{}) (stats
 		urls = RewriteURLHost(urls, config.Host)
 	}
 
+	if config.Scope.MaxHostnamesPerDomain > 0 && config.Scope.state == nil {
+		config.Scope.state = &scopeState{}
+	}
+
+	if config.HTTP.network == nil {
+		config.HTTP.network = newNetworkState(config.HTTP)
+	}
+
+	if config.MetricsAddr != "" {
+		go func() {
+			if err := metrics.Serve(config.MetricsAddr); err != nil {
+				log.Error("metrics server stopped: ", err)
+			}
+		}()
+	}
+
+	if len(config.Seeds) > 0 {
+		urls = mergeProviderSeeds(urls, config.Seeds)
+	}
+
+	if config.HTTP.RespectRobots {
+		urls = append(urls, discoverSitemapSeeds(urls, config.HTTP)...)
+	}
+
+	if config.WARC.OutputPath != "" {
+		warcWriter, warcErr := NewWARCWriter(config.WARC.OutputPath)
+		if warcErr != nil {
+			log.Error("error opening WARC output: ", warcErr)
+		} else {
+			config.HTTP.WARCWriter = warcWriter
+			defer warcWriter.Close()
+		}
+	}
+
 	config.HTTP.ParseLinks = config.Links.CrawlExternalLinks || config.Links.CrawlHyperlinks ||
-		config.Links.CrawlImages
-	results, stats, server200TimeSum := crawlUrls(urls, config, quit)
+		config.Links.CrawlImages || config.MaxDepth > 0
 
-	if config.HTTP.ParseLinks {
-		_, pageLinksStats, linksServer200TimeSum := crawlPageLinks(results, config, quit)
-		stats = MergeCrawlStats(stats, pageLinksStats)
-		server200TimeSum += linksServer200TimeSum
+	var server200TimeSum time.Duration
+	if config.MaxDepth > 0 {
+		stats, server200TimeSum = crawlRecursive(urls, config, quit)
+	} else {
+		var results map[string]*HTTPResponse
+		results, stats, server200TimeSum = crawlUrls(urls, config, quit)
+
+		if config.HTTP.ParseLinks {
+			_, pageLinksStats, linksServer200TimeSum := crawlPageLinks(results, config, quit)
+			stats = MergeCrawlStats(stats, pageLinksStats)
+			server200TimeSum += linksServer200TimeSum
+		}
 	}
 
 	total200 := stats.StatusCodes[200]
@@ -192,6 +312,76 @@ func crawlPageLinks(sourceResults map[string]*HTTPResponse, sourceConfig CrawlCo
 	return linksResults, linksStats, linksServer200TimeSum
 }
 
+// crawlRecursive drives a recursive crawl as a producer/consumer loop
+// over config.Queue: it pops a batch of pending URLs, fetches them
+// concurrently, and enqueues newly discovered links up to config.MaxDepth,
+// filtered through config.Scope. Backing the frontier with a persistent
+// Queue lets the crawl be interrupted and picked back up with --resume.
+func crawlRecursive(seeds []string, config CrawlConfig, quit <-chan struct{}) (stats CrawlStats, server200TimeSum time.Duration) {
+	queue := config.Queue
+	if queue == nil {
+		queue = NewMemoryQueue()
+	}
+
+	if !config.Resume {
+		for _, seed := range seeds {
+			queue.Push(QueueItem{URL: seed, Depth: 0})
+		}
+	}
+
+	stats.StatusCodes = make(map[int]int)
+
+	for {
+		select {
+		case <-quit:
+			log.Info("Waiting for workers to finish...")
+			return
+		default:
+		}
+
+		batch := make([]QueueItem, 0, config.Throttle)
+		depths := make(map[string]int, config.Throttle)
+		for len(batch) < config.Throttle {
+			item, ok := queue.Pop()
+			if !ok {
+				break
+			}
+			batch = append(batch, item)
+			depths[item.URL] = item.Depth
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		metrics.QueueDepth.Set(float64(queue.Len()))
+
+		batchUrls := make([]string, len(batch))
+		for i, item := range batch {
+			batchUrls[i] = item.URL
+		}
+
+		resultChan := config.HTTPGetter.ConcurrentHTTPGet(batchUrls, config.HTTP, config.Throttle, quit)
+		for result := range resultChan {
+			populateCrawlStats(result, &stats, &server200TimeSum)
+			if err := queue.MarkDone(result.URL); err != nil {
+				log.Error("error marking URL done: ", err)
+			}
+
+			depth := depths[result.URL]
+			if depth >= config.MaxDepth {
+				continue
+			}
+
+			for _, link := range result.Links {
+				if !config.Scope.Allowed(link.TargetURL) {
+					continue
+				}
+				queue.Push(QueueItem{URL: link.TargetURL.String(), Depth: depth + 1})
+			}
+		}
+	}
+}
+
 func crawlUrls(urls []string, config CrawlConfig, quit <-chan struct{}) (results map[string]*HTTPResponse,
 	stats CrawlStats, server200TimeSum time.Duration) {
 
@@ -216,6 +406,21 @@ func populateCrawlStats(result *HTTPResponse, stats *CrawlStats, total200Time *t
 
 	stats.StatusCodes[statusCode]++
 
+	// Under RaceMode, raceGet already observes every attempt itself, so
+	// observing result here too would double-count its representative
+	// attempt.
+	if len(result.RaceResults) == 0 {
+		observeRequestMetrics(result)
+	}
+
+	if len(result.RaceResults) > 1 && raceResultsDiverge(result.RaceResults) {
+		stats.RaceAnomalies = append(stats.RaceAnomalies, CrawlResult{
+			URL:        result.URL,
+			Time:       serverTime,
+			StatusCode: statusCode,
+		})
+	}
+
 	if statusCode == 200 {
 		*total200Time += serverTime
 
@@ -230,3 +435,15 @@ func populateCrawlStats(result *HTTPResponse, stats *CrawlStats, total200Time *t
 		})
 	}
 }
+
+// raceResultsDiverge reports whether a RaceMode burst saw more than one
+// distinct status code across its simultaneous attempts.
+func raceResultsDiverge(results []*HTTPResponse) bool {
+	first := results[0].StatusCode
+	for _, attempt := range results[1:] {
+		if attempt.StatusCode != first {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"net/url"
+	"testing"
+)
+
+// fakeConcurrentGetter returns canned responses by URL instead of making
+// real requests, and records every URL crawlRecursive asks it to fetch,
+// so tests can assert which links it followed.
+type fakeConcurrentGetter struct {
+	responses map[string]*HTTPResponse
+	requested []string
+}
+
+func (g *fakeConcurrentGetter) ConcurrentHTTPGet(urls []string, config HTTPConfig,
+	maxConcurrent int, quit <-chan struct{}) <-chan *HTTPResponse {
+
+	ch := make(chan *HTTPResponse, len(urls))
+	for _, u := range urls {
+		g.requested = append(g.requested, u)
+		resp, ok := g.responses[u]
+		if !ok {
+			resp = &HTTPResponse{URL: u, StatusCode: 200}
+		}
+		ch <- resp
+	}
+	close(ch)
+	return ch
+}
+
+func link(t *testing.T, raw string) Link {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", raw, err)
+	}
+	return Link{TargetURL: u}
+}
+
+func TestCrawlRecursiveStopsAtMaxDepthAndScope(t *testing.T) {
+	getter := &fakeConcurrentGetter{
+		responses: map[string]*HTTPResponse{
+			"https://example.com/a": {
+				URL:        "https://example.com/a",
+				StatusCode: 200,
+				Links: []Link{
+					link(t, "https://example.com/b"),
+					link(t, "https://other.com/c"),
+				},
+			},
+			"https://example.com/b": {
+				URL:        "https://example.com/b",
+				StatusCode: 200,
+				// d is one hop past MaxDepth, so it must never be fetched.
+				Links: []Link{link(t, "https://example.com/d")},
+			},
+		},
+	}
+
+	config := CrawlConfig{
+		Throttle:   10,
+		HTTPGetter: getter,
+		MaxDepth:   1,
+		Scope:      ScopePolicy{SeedHost: "example.com"},
+	}
+
+	stats, _ := crawlRecursive([]string{"https://example.com/a"}, config, nil)
+
+	if stats.Total != 2 {
+		t.Errorf("stats.Total = %d, want 2 (a and its in-scope link b)", stats.Total)
+	}
+
+	for _, excluded := range []string{"https://other.com/c", "https://example.com/d"} {
+		for _, requested := range getter.requested {
+			if requested == excluded {
+				t.Errorf("crawlRecursive fetched %q, want it excluded by Scope/MaxDepth", excluded)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// QueueItem is a single URL tracked by a Queue, along with the depth it
+// was discovered at and whether it has been crawled yet. Persistent
+// Queue implementations store exactly this tuple so an interrupted
+// crawl can resume where it left off.
+type QueueItem struct {
+	URL    string
+	Depth  int
+	Status string
+}
+
+// Queue item statuses.
+const (
+	QueueStatusPending    = "pending"
+	QueueStatusInProgress = "in-progress"
+	QueueStatusDone       = "done"
+)
+
+// Queue is the pluggable frontier backing a recursive AsyncCrawl.
+// Implementations must be safe for concurrent use, and must deduplicate
+// URLs so a link discovered twice is only crawled once.
+type Queue interface {
+	// Push enqueues item if its URL hasn't been seen before. It returns
+	// false if the URL was already known, in which case the item is not
+	// re-queued.
+	Push(item QueueItem) bool
+	// Pop removes and returns the next pending item. ok is false once
+	// the queue has no pending items left.
+	Pop() (item QueueItem, ok bool)
+	// MarkDone records that url finished crawling, so a resumed crawl
+	// won't re-enqueue it.
+	MarkDone(url string) error
+	// Len reports how many items are currently pending, for the
+	// crowlet_queue_depth metric.
+	Len() int
+	// Close releases any resources held by the queue.
+	Close() error
+}
+
+// MemoryQueue is the default in-memory Queue. It does not survive
+// process restarts, so --resume has no effect unless a persistent Queue
+// implementation is configured instead.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	pending []QueueItem
+	seen    map[string]struct{}
+}
+
+// NewMemoryQueue returns an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{seen: make(map[string]struct{})}
+}
+
+// Push implements Queue.
+func (q *MemoryQueue) Push(item QueueItem) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.seen[item.URL]; ok {
+		return false
+	}
+
+	q.seen[item.URL] = struct{}{}
+	item.Status = QueueStatusPending
+	q.pending = append(q.pending, item)
+	return true
+}
+
+// Pop implements Queue.
+func (q *MemoryQueue) Pop() (item QueueItem, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return QueueItem{}, false
+	}
+
+	item, q.pending = q.pending[0], q.pending[1:]
+	return item, true
+}
+
+// MarkDone implements Queue. MemoryQueue keeps no record of completion
+// beyond removing the item from pending in Pop, so this is a no-op.
+func (q *MemoryQueue) MarkDone(url string) error {
+	return nil
+}
+
+// Len implements Queue.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+// Close implements Queue.
+func (q *MemoryQueue) Close() error {
+	return nil
+}
+
+// ScopePolicy decides whether a URL discovered while recursively
+// crawling should be added to the frontier. A zero-value ScopePolicy
+// allows every URL. ScopePolicy is safe to copy — it's carried around by
+// value inside CrawlConfig, which itself gets copied throughout
+// AsyncCrawl — because its mutable bookkeeping lives behind the state
+// pointer rather than in the struct itself.
+type ScopePolicy struct {
+	// SeedHost, if set, requires discovered URLs to share this host.
+	SeedHost string
+	// AllowPatterns, if non-empty, requires a discovered URL to match
+	// at least one of these patterns.
+	AllowPatterns []*regexp.Regexp
+	// DenyPatterns rejects any discovered URL matching one of these
+	// patterns, regardless of AllowPatterns.
+	DenyPatterns []*regexp.Regexp
+	// MaxHostnamesPerDomain caps how many distinct hostnames under the
+	// same registered domain (e.g. *.blogspot.com) may be queued, to
+	// avoid subdomain blowups. 0 means unlimited.
+	MaxHostnamesPerDomain int
+
+	state *scopeState
+}
+
+// scopeState holds the per-domain hostname counts ScopePolicy needs to
+// enforce MaxHostnamesPerDomain. It must be initialized once per crawl
+// (AsyncCrawl does this) so every copy of a ScopePolicy value shares the
+// same counts instead of each starting its own.
+type scopeState struct {
+	mu        sync.Mutex
+	hostnames map[string]map[string]struct{}
+}
+
+// Allowed reports whether target may be added to the frontier.
+func (p ScopePolicy) Allowed(target *url.URL) bool {
+	if p.SeedHost != "" && target.Hostname() != p.SeedHost {
+		return false
+	}
+
+	targetStr := target.String()
+
+	for _, deny := range p.DenyPatterns {
+		if deny.MatchString(targetStr) {
+			return false
+		}
+	}
+
+	if len(p.AllowPatterns) > 0 {
+		allowed := false
+		for _, allow := range p.AllowPatterns {
+			if allow.MatchString(targetStr) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	return p.allowHostname(target.Hostname())
+}
+
+func (p ScopePolicy) allowHostname(hostname string) bool {
+	if p.MaxHostnamesPerDomain <= 0 || p.state == nil {
+		return true
+	}
+
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+
+	if p.state.hostnames == nil {
+		p.state.hostnames = make(map[string]map[string]struct{})
+	}
+
+	domain := registeredDomain(hostname)
+	seen := p.state.hostnames[domain]
+	if seen == nil {
+		seen = make(map[string]struct{})
+		p.state.hostnames[domain] = seen
+	}
+
+	if _, ok := seen[hostname]; ok {
+		return true
+	}
+
+	if len(seen) >= p.MaxHostnamesPerDomain {
+		return false
+	}
+
+	seen[hostname] = struct{}{}
+	return true
+}
+
+// registeredDomain returns a best-effort registered domain for hostname
+// (its last two labels), used to group subdomains like a.blogspot.com
+// and b.blogspot.com under "blogspot.com". It's a simplification that
+// doesn't consult a public suffix list, so multi-label TLDs (co.uk)
+// aren't handled precisely.
+func registeredDomain(hostname string) string {
+	labels := strings.Split(hostname, ".")
+	if len(labels) <= 2 {
+		return hostname
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", raw, err)
+	}
+	return u
+}
+
+func TestScopePolicyAllowedSeedHost(t *testing.T) {
+	policy := ScopePolicy{SeedHost: "example.com"}
+
+	if !policy.Allowed(mustParseURL(t, "https://example.com/page")) {
+		t.Errorf("Allowed() = false for matching host, want true")
+	}
+	if policy.Allowed(mustParseURL(t, "https://other.com/page")) {
+		t.Errorf("Allowed() = true for non-matching host, want false")
+	}
+}
+
+func TestScopePolicyDenyOverridesAllow(t *testing.T) {
+	policy := ScopePolicy{
+		AllowPatterns: []*regexp.Regexp{regexp.MustCompile(`.*`)},
+		DenyPatterns:  []*regexp.Regexp{regexp.MustCompile(`/private/`)},
+	}
+
+	if !policy.Allowed(mustParseURL(t, "https://example.com/public/page")) {
+		t.Errorf("Allowed() = false for allowed page, want true")
+	}
+	if policy.Allowed(mustParseURL(t, "https://example.com/private/page")) {
+		t.Errorf("Allowed() = true for denied page despite matching AllowPatterns, want false")
+	}
+}
+
+func TestScopePolicyRequiresAllowPatternMatch(t *testing.T) {
+	policy := ScopePolicy{
+		AllowPatterns: []*regexp.Regexp{regexp.MustCompile(`/blog/`)},
+	}
+
+	if !policy.Allowed(mustParseURL(t, "https://example.com/blog/post")) {
+		t.Errorf("Allowed() = false for URL matching AllowPatterns, want true")
+	}
+	if policy.Allowed(mustParseURL(t, "https://example.com/about")) {
+		t.Errorf("Allowed() = true for URL matching no AllowPatterns, want false")
+	}
+}
+
+func TestScopePolicyMaxHostnamesPerDomain(t *testing.T) {
+	policy := ScopePolicy{
+		MaxHostnamesPerDomain: 1,
+		state:                 &scopeState{},
+	}
+
+	if !policy.Allowed(mustParseURL(t, "https://a.blogspot.com/page")) {
+		t.Fatalf("Allowed() = false for first hostname, want true")
+	}
+	if policy.Allowed(mustParseURL(t, "https://b.blogspot.com/page")) {
+		t.Errorf("Allowed() = true for second hostname over the cap, want false")
+	}
+	// A second URL on the already-counted hostname is still allowed.
+	if !policy.Allowed(mustParseURL(t, "https://a.blogspot.com/other")) {
+		t.Errorf("Allowed() = false for a second URL on the counted hostname, want true")
+	}
+}
+
+func TestMemoryQueuePushDeduplicates(t *testing.T) {
+	queue := NewMemoryQueue()
+
+	if !queue.Push(QueueItem{URL: "https://example.com/a"}) {
+		t.Fatalf("first Push() = false, want true")
+	}
+	if queue.Push(QueueItem{URL: "https://example.com/a"}) {
+		t.Fatalf("second Push() of the same URL = true, want false")
+	}
+	if queue.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", queue.Len())
+	}
+}
+
+func TestMemoryQueuePopFIFO(t *testing.T) {
+	queue := NewMemoryQueue()
+	queue.Push(QueueItem{URL: "https://example.com/a", Depth: 0})
+	queue.Push(QueueItem{URL: "https://example.com/b", Depth: 1})
+
+	first, ok := queue.Pop()
+	if !ok || first.URL != "https://example.com/a" {
+		t.Fatalf("Pop() = %+v, %v, want a", first, ok)
+	}
+
+	second, ok := queue.Pop()
+	if !ok || second.URL != "https://example.com/b" {
+		t.Fatalf("Pop() = %+v, %v, want b", second, ok)
+	}
+
+	if _, ok := queue.Pop(); ok {
+		t.Fatalf("Pop() on an empty queue returned ok, want false")
+	}
+}
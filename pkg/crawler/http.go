@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"net/url"
@@ -10,8 +11,15 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/tcnksm/go-httpstat"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/talkhot/crowlet/pkg/metrics"
+	"github.com/talkhot/crowlet/pkg/robots"
 )
 
+var tracer = otel.Tracer("github.com/talkhot/crowlet/pkg/crawler")
+
 // HTTPResponse holds information from a GET to a specific URL
 type HTTPResponse struct {
 	URL        string
@@ -21,6 +29,12 @@ type HTTPResponse struct {
 	EndTime    time.Time
 	Err        error
 	Links      []Link
+
+	// RaceResults holds every attempt fired for this URL when
+	// HTTPConfig.RaceMode is greater than 1, so callers can compare
+	// status codes across simultaneous requests. Its first element is
+	// this response itself. It is left nil when racing is disabled.
+	RaceResults []*HTTPResponse
 }
 
 // HTTPConfig holds settings used to get pages via HTTP/S
@@ -30,6 +44,68 @@ type HTTPConfig struct {
 	Timeout      time.Duration
 	ParseLinks   bool
 	CustomHeader string // New field: set this to a string in the format "Key: Value"
+	WARCWriter   *WARCWriter
+
+	// RespectRobots gates every request in RunConcurrentGet through the
+	// target host's robots.txt, honoring Disallow/Allow and serializing
+	// requests to hosts that declare a Crawl-delay.
+	RespectRobots bool
+	// UserAgent is matched against robots.txt "User-agent" groups, and
+	// sent as the request's User-Agent header when set.
+	UserAgent string
+
+	// PerHostRPS, when greater than 0, caps requests to any single host
+	// to this many per second, independently of the global Throttle.
+	PerHostRPS float64
+	// PerHostBurst is the token bucket burst size backing PerHostRPS.
+	// Defaults to 1 when PerHostRPS is set and PerHostBurst isn't.
+	PerHostBurst int
+	// MaxRetries is how many extra attempts a URL gets after a 429 or
+	// 503 response, on top of the initial attempt.
+	MaxRetries int
+
+	// RaceMode, when greater than 1, fires this many simultaneous
+	// requests per URL instead of one, to probe for concurrency bugs
+	// and idempotency violations.
+	RaceMode int
+
+	// network holds the robots.txt cache, per-host Crawl-delay throttle
+	// and adaptive rate limiters for a crawl. AsyncCrawl initializes it
+	// once so this state survives across the multiple RunConcurrentGet
+	// calls one crawl makes (each crawlRecursive batch, plus the
+	// link-following pass) instead of being rebuilt, and lost, every
+	// time. Left nil, RunConcurrentGet falls back to building its own
+	// call-scoped state, for callers that invoke it directly.
+	network *networkState
+}
+
+// networkState holds the per-crawl state RunConcurrentGet needs to
+// persist across calls: the robots.txt cache, the Crawl-delay throttle
+// and the adaptive per-host rate limiters.
+type networkState struct {
+	robots   *robots.Cache
+	throttle *hostThrottle
+	limiters *hostLimiters
+}
+
+// newNetworkState builds the networkState for a crawl, sizing the
+// robots.txt cache and rate limiters according to config.
+func newNetworkState(config HTTPConfig) *networkState {
+	state := &networkState{throttle: newHostThrottle()}
+
+	if config.RespectRobots {
+		state.robots = robots.NewCache()
+	}
+
+	if config.PerHostRPS > 0 {
+		burst := config.PerHostBurst
+		if burst <= 0 {
+			burst = 1
+		}
+		state.limiters = newHostLimiters(config.PerHostRPS, burst)
+	}
+
+	return state
 }
 
 // HTTPGetter performs a single HTTP/S request to the URL and returns information
@@ -55,6 +131,9 @@ func configureRequest(req *http.Request, config HTTPConfig) {
 	if len(config.User) > 0 {
 		req.SetBasicAuth(config.User, config.Pass)
 	}
+	if len(config.UserAgent) > 0 {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
 	if len(config.CustomHeader) > 0 {
 		parts := strings.SplitN(config.CustomHeader, ":", 2)
 		if len(parts) == 2 {
@@ -69,30 +148,61 @@ func configureRequest(req *http.Request, config HTTPConfig) {
 
 // HTTPGet issues a GET request to a single URL and returns an HTTPResponse
 func HTTPGet(client *http.Client, urlStr string, config HTTPConfig) (response *HTTPResponse) {
-	response = &HTTPResponse{
-		URL: urlStr,
-	}
-
 	req, result, err := createRequest(urlStr)
 	if err != nil {
-		response.Err = err
-		return
+		return &HTTPResponse{URL: urlStr, Err: err}
 	}
 
 	configureRequest(req, config)
 
+	return doRequest(client, req, result, urlStr, config)
+}
+
+// doRequest fires the already-built req via client.Do and assembles the
+// HTTPResponse: tracing, the httpstat timings collected via req's
+// context, and (when needed) buffering the body to feed ExtractLinks
+// and the WARC writer. It's split out of HTTPGet so raceGet can build
+// every attempt's request ahead of time and gate only this call, the
+// part whose timing actually matters for its "starting pistol" pattern.
+func doRequest(client *http.Client, req *http.Request, result *httpstat.Result, urlStr string, config HTTPConfig) (response *HTTPResponse) {
+	response = &HTTPResponse{
+		URL: urlStr,
+	}
+
+	ctx, span := tracer.Start(req.Context(), "HTTPGet")
+	span.SetAttributes(attribute.String("http.url", urlStr))
+	req = req.WithContext(ctx)
+
+	metrics.InflightRequests.Inc()
 	resp, err := client.Do(req)
+	metrics.InflightRequests.Dec()
+
 	response.EndTime = time.Now()
 	response.Response = resp
 	response.Result = result
 
+	var body bytes.Buffer
+	needsBody := config.ParseLinks || config.WARCWriter != nil
+
 	defer func() {
 		if resp != nil {
-			if !config.ParseLinks {
+			if !needsBody {
 				io.Copy(io.Discard, resp.Body)
 			}
 			resp.Body.Close()
 		}
+
+		span.SetAttributes(attribute.Int("http.status_code", response.StatusCode))
+		if result != nil {
+			span.SetAttributes(
+				attribute.Int64("httpstat.dns_ms", result.DNSLookup.Milliseconds()),
+				attribute.Int64("httpstat.tcp_ms", result.TCPConnection.Milliseconds()),
+				attribute.Int64("httpstat.tls_ms", result.TLSHandshake.Milliseconds()),
+				attribute.Int64("httpstat.server_ms", result.ServerProcessing.Milliseconds()),
+			)
+		}
+		span.End()
+
 		PrintResult(response)
 	}()
 
@@ -109,6 +219,20 @@ func HTTPGet(client *http.Client, urlStr string, config HTTPConfig) (response *H
 		return
 	}
 
+	if needsBody {
+		// Buffer the body once so it can feed both ExtractLinks and the
+		// WARC writer without a second read of resp.Body.
+		if _, err := io.Copy(io.Discard, io.TeeReader(resp.Body, &body)); err != nil {
+			log.Error("error reading response body:", err)
+		}
+	}
+
+	if config.WARCWriter != nil {
+		if err := config.WARCWriter.WriteExchange(urlStr, req, resp, body.Bytes()); err != nil {
+			log.Error("error writing WARC record:", err)
+		}
+	}
+
 	if config.ParseLinks {
 		currentURL, err := url.Parse(urlStr)
 		if err != nil {
@@ -116,7 +240,7 @@ func HTTPGet(client *http.Client, urlStr string, config HTTPConfig) (response *H
 			return
 		}
 
-		response.Links, err = ExtractLinks(resp.Body, *currentURL)
+		response.Links, err = ExtractLinks(bytes.NewReader(body.Bytes()), *currentURL)
 		if err != nil {
 			log.Error("error extracting page links:", err)
 			return
@@ -126,6 +250,22 @@ func HTTPGet(client *http.Client, urlStr string, config HTTPConfig) (response *H
 	return
 }
 
+// observeRequestMetrics records result's timings against the
+// crowlet_requests_total/crowlet_request_duration_seconds metrics, if a
+// request was actually built and sent for it; one that failed without a
+// response is counted with status code 0. It's shared by
+// populateCrawlStats, fetchWithRetry and raceGet so every attempt a crawl
+// fires is reflected in those metrics, including the retried ones and
+// the ones RaceMode discards in favor of a representative result.
+func observeRequestMetrics(result *HTTPResponse) {
+	if result.Result == nil {
+		return
+	}
+	metrics.ObserveRequest(hostOf(result.URL), result.StatusCode,
+		result.Result.DNSLookup, result.Result.TCPConnection, result.Result.TLSHandshake,
+		result.Result.ServerProcessing, result.Result.ContentTransfer(result.EndTime))
+}
+
 // ConcurrentHTTPGetter allows concurrent execution of an HTTPGetter
 type ConcurrentHTTPGetter interface {
 	ConcurrentHTTPGet(urls []string, config HTTPConfig, maxConcurrent int,
@@ -161,17 +301,31 @@ func RunConcurrentGet(httpGet HTTPGetter, urls []string, config HTTPConfig,
 		}
 	}
 
+	state := config.network
+	if state == nil {
+		state = newNetworkState(config)
+	}
+	robotsCache := state.robots
+	delays := state.throttle
+	limiters := state.limiters
+
 	defer func() {
 		wg.Wait()
 		close(resultChan)
 	}()
 
-	for _, url := range urls {
+	for _, targetURL := range urls {
 		select {
 		case <-quit:
 			log.Info("Waiting for workers to finish...")
 			return
 		case client := <-clientsReady:
+			if robotsCache != nil && !robotsCache.Allowed(config.UserAgent, targetURL) {
+				log.WithField("url", targetURL).Info("blocked by robots.txt")
+				clientsReady <- client
+				continue
+			}
+
 			wg.Add(1)
 
 			go func(client *http.Client, url string) {
@@ -180,8 +334,16 @@ func RunConcurrentGet(httpGet HTTPGetter, urls []string, config HTTPConfig,
 					wg.Done()
 				}()
 
-				resultChan <- httpGet(client, url, config)
-			}(client, url)
+				if robotsCache != nil {
+					delays.wait(url, robotsCache.CrawlDelay(config.UserAgent, url))
+				}
+
+				if config.RaceMode > 1 {
+					resultChan <- raceGet(client, url, config, config.RaceMode)
+				} else {
+					resultChan <- fetchWithRetry(httpGet, client, url, config, limiters)
+				}
+			}(client, targetURL)
 		}
 	}
 }
@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/tcnksm/go-httpstat"
+)
+
+// raceGet fires n simultaneous requests at url, all held behind a single
+// sync.WaitGroup "starting pistol" so they leave as close to the same
+// instant as possible, and returns one of them with RaceResults
+// populated by every attempt. This lets AsyncCrawl surface status-code
+// divergence between attempts (e.g. one 200 among 5xx) as a signal of
+// concurrency bugs or idempotency violations at the target.
+//
+// Every request is built before the goroutines block on the gate, so
+// the only thing timed by the gate is client.Do itself — building the
+// request inside the gated section would reintroduce the per-goroutine
+// scheduling/allocation jitter the gate exists to eliminate.
+func raceGet(client *http.Client, url string, config HTTPConfig, n int) *HTTPResponse {
+	var starter sync.WaitGroup
+	var attempts sync.WaitGroup
+	starter.Add(1)
+	attempts.Add(n)
+
+	results := make([]*HTTPResponse, n)
+	for i := 0; i < n; i++ {
+		req, result, err := createRequest(url)
+		if err != nil {
+			attempts.Done()
+			results[i] = &HTTPResponse{URL: url, Err: err}
+			continue
+		}
+		configureRequest(req, config)
+
+		go func(i int, req *http.Request, result *httpstat.Result) {
+			defer attempts.Done()
+			starter.Wait()
+			results[i] = doRequest(client, req, result, url, config)
+			observeRequestMetrics(results[i])
+		}(i, req, result)
+	}
+
+	starter.Done()
+	attempts.Wait()
+
+	representative := results[0]
+	representative.RaceResults = results
+	return representative
+}
@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minHostRPS is the floor a host's adaptive rate is never shrunk below,
+// so a misbehaving host still gets retried eventually instead of
+// stalling forever.
+const minHostRPS = 0.05
+
+// recoverStepRPS is how much a host's rate grows, per successful 2xx
+// response, back towards its configured PerHostRPS.
+const recoverStepRPS = 0.1
+
+// hostLimiters maintains one adaptive rate.Limiter per host, seeded from
+// HTTPConfig.PerHostRPS/PerHostBurst. A 429 or 503 response shrinks the
+// offending host's rate (multiplicative decrease, honoring Retry-After
+// when present); a 2xx response grows it back slowly (additive
+// increase), capped at the originally configured rate.
+type hostLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	baseRPS  float64
+	burst    int
+}
+
+func newHostLimiters(baseRPS float64, burst int) *hostLimiters {
+	return &hostLimiters{
+		limiters: make(map[string]*rate.Limiter),
+		baseRPS:  baseRPS,
+		burst:    burst,
+	}
+}
+
+func (h *hostLimiters) get(host string) *rate.Limiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(h.baseRPS), h.burst)
+		h.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// wait blocks until rawURL's host limiter admits another request.
+func (h *hostLimiters) wait(rawURL string) {
+	h.get(hostOf(rawURL)).Wait(context.Background())
+}
+
+// backoff halves host's rate limit, or shrinks it to match
+// retryAfter if that's even more conservative.
+func (h *hostLimiters) backoff(host string, retryAfter time.Duration) {
+	limiter := h.get(host)
+	next := limiter.Limit() / 2
+
+	if retryAfter > 0 {
+		if fromRetryAfter := rate.Limit(1 / retryAfter.Seconds()); fromRetryAfter < next {
+			next = fromRetryAfter
+		}
+	}
+	if next < minHostRPS {
+		next = minHostRPS
+	}
+
+	limiter.SetLimit(next)
+}
+
+// recover grows host's rate limit by recoverStepRPS, capped at the
+// originally configured PerHostRPS.
+func (h *hostLimiters) recover(host string) {
+	limiter := h.get(host)
+	capped := rate.Limit(h.baseRPS)
+
+	next := limiter.Limit() + recoverStepRPS
+	if next > capped {
+		next = capped
+	}
+
+	limiter.SetLimit(next)
+}
+
+// fetchWithRetry calls httpGet, retrying up to config.MaxRetries times
+// when the response is a 429 or 503. When limiters is non-nil, it waits
+// for that host's token before each attempt and adapts the host's rate
+// based on the outcome.
+func fetchWithRetry(httpGet HTTPGetter, client *http.Client, rawURL string, config HTTPConfig, limiters *hostLimiters) (response *HTTPResponse) {
+	attempts := config.MaxRetries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if limiters != nil {
+			limiters.wait(rawURL)
+		}
+
+		response = httpGet(client, rawURL, config)
+
+		if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+			if limiters != nil && response.StatusCode >= 200 && response.StatusCode < 300 {
+				limiters.recover(hostOf(rawURL))
+			}
+			return response
+		}
+
+		if limiters != nil {
+			limiters.backoff(hostOf(rawURL), parseRetryAfter(response.Response))
+		}
+
+		// Only the final attempt reaches populateCrawlStats, so an
+		// attempt that is about to be retried is observed here.
+		if attempt < attempts-1 {
+			observeRequestMetrics(response)
+		}
+	}
+
+	return response
+}
+
+// parseRetryAfter reads the Retry-After header from resp, supporting
+// both the delay-seconds and HTTP-date forms.
+func parseRetryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+
+	return 0
+}
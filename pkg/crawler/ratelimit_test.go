@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestHostLimitersBackoffHalvesRate(t *testing.T) {
+	limiters := newHostLimiters(2.0, 1)
+
+	limiters.backoff("example.com", 0)
+
+	if got := limiters.get("example.com").Limit(); got != rate.Limit(1.0) {
+		t.Errorf("Limit() after backoff = %v, want 1.0", got)
+	}
+}
+
+func TestHostLimitersBackoffHonorsRetryAfter(t *testing.T) {
+	limiters := newHostLimiters(2.0, 1)
+
+	// A 10s Retry-After implies a rate of 0.1/s, far more conservative
+	// than the plain halving (1.0/s), so it should win.
+	limiters.backoff("example.com", 10*time.Second)
+
+	if got := limiters.get("example.com").Limit(); got != rate.Limit(0.1) {
+		t.Errorf("Limit() after backoff with Retry-After = %v, want 0.1", got)
+	}
+}
+
+func TestHostLimitersBackoffNeverBelowFloor(t *testing.T) {
+	limiters := newHostLimiters(0.01, 1)
+
+	limiters.backoff("example.com", 0)
+
+	if got := limiters.get("example.com").Limit(); got != rate.Limit(minHostRPS) {
+		t.Errorf("Limit() after backoff = %v, want the minHostRPS floor %v", got, minHostRPS)
+	}
+}
+
+func TestHostLimitersRecoverCappedAtBaseRPS(t *testing.T) {
+	limiters := newHostLimiters(1.0, 1)
+
+	limiters.backoff("example.com", 0) // 1.0 -> 0.5
+	limiters.recover("example.com")    // 0.5 -> 0.6
+	limiters.recover("example.com")    // 0.6 -> 0.7
+
+	for i := 0; i < 10; i++ {
+		limiters.recover("example.com")
+	}
+
+	if got := limiters.get("example.com").Limit(); got != rate.Limit(1.0) {
+		t.Errorf("Limit() after repeated recover = %v, want capped at baseRPS 1.0", got)
+	}
+}
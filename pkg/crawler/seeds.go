@@ -0,0 +1,292 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SeedProvider discovers URLs to seed a crawl from. AsyncCrawl merges
+// the output of every configured SeedProvider into its URL list, so a
+// crawl isn't limited to whatever the current sitemap.xml lists.
+type SeedProvider interface {
+	Seeds() ([]*url.URL, error)
+}
+
+// SitemapSeedProvider reads sitemap.xml at SitemapURL. When
+// MaxIndexDepth is greater than 0, it also follows
+// <sitemapindex><sitemap><loc> entries that many levels deep.
+type SitemapSeedProvider struct {
+	SitemapURL    string
+	MaxIndexDepth int
+}
+
+// Seeds implements SeedProvider.
+func (p SitemapSeedProvider) Seeds() ([]*url.URL, error) {
+	if p.MaxIndexDepth <= 0 {
+		return GetSitemapUrls(p.SitemapURL)
+	}
+	return collectSitemapSeeds(p.SitemapURL, p.MaxIndexDepth)
+}
+
+type sitemapIndexDoc struct {
+	XMLName  xml.Name            `xml:"sitemapindex"`
+	Sitemaps []sitemapIndexEntry `xml:"sitemap"`
+}
+
+type sitemapIndexEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type urlsetDoc struct {
+	XMLName xml.Name `xml:"urlset"`
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+// collectSitemapSeeds fetches sitemapURL and, if it's a sitemapindex,
+// recurses into each listed sitemap up to depthRemaining levels;
+// otherwise it parses it as a plain urlset.
+func collectSitemapSeeds(sitemapURL string, depthRemaining int) ([]*url.URL, error) {
+	resp, err := http.Get(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index sitemapIndexDoc
+	if xml.Unmarshal(body, &index) == nil && len(index.Sitemaps) > 0 {
+		var urls []*url.URL
+		for _, entry := range index.Sitemaps {
+			if depthRemaining <= 0 {
+				log.Warn("sitemap index nested deeper than MaxIndexDepth, skipping ", entry.Loc)
+				continue
+			}
+
+			nested, err := collectSitemapSeeds(entry.Loc, depthRemaining-1)
+			if err != nil {
+				log.Error("error following sitemap index entry: ", err)
+				continue
+			}
+			urls = append(urls, nested...)
+		}
+		return urls, nil
+	}
+
+	var urlset urlsetDoc
+	if err := xml.Unmarshal(body, &urlset); err != nil {
+		return nil, err
+	}
+
+	var urls []*url.URL
+	for _, entry := range urlset.URLs {
+		parsed, err := url.Parse(entry.Loc)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		urls = append(urls, parsed)
+	}
+	return urls, nil
+}
+
+// WaybackSeedProvider finds a domain's historical URLs via the Wayback
+// Machine's CDX API, for crawling URLs a site's current sitemap no
+// longer lists.
+type WaybackSeedProvider struct {
+	Domain string
+	// From and To restrict the capture date range, in CDX's yyyyMMdd
+	// form. Either may be left empty.
+	From, To string
+	// StatusFilter, if non-empty, restricts results to captures with
+	// one of these original HTTP status codes.
+	StatusFilter []int
+}
+
+// Seeds implements SeedProvider.
+func (p WaybackSeedProvider) Seeds() ([]*url.URL, error) {
+	endpoint := fmt.Sprintf("http://web.archive.org/cdx/search/cdx?url=%s/*&output=json", url.QueryEscape(p.Domain))
+	if p.From != "" {
+		endpoint += "&from=" + p.From
+	}
+	if p.To != "" {
+		endpoint += "&to=" + p.To
+	}
+
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rows [][]string
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	return parseCDXRows(rows, p.StatusFilter)
+}
+
+// parseCDXRows turns a CDX JSON response (a header row followed by data
+// rows) into deduplicated URLs, optionally filtered by status code.
+func parseCDXRows(rows [][]string, statusFilter []int) ([]*url.URL, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	originalIdx, statusIdx := -1, -1
+	for i, column := range rows[0] {
+		switch column {
+		case "original":
+			originalIdx = i
+		case "statuscode":
+			statusIdx = i
+		}
+	}
+	if originalIdx == -1 {
+		return nil, errors.New("unexpected CDX response: missing 'original' column")
+	}
+
+	allowedStatus := make(map[string]struct{}, len(statusFilter))
+	for _, code := range statusFilter {
+		allowedStatus[strconv.Itoa(code)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{})
+	var urls []*url.URL
+	for _, row := range rows[1:] {
+		if len(allowedStatus) > 0 && statusIdx != -1 {
+			if _, ok := allowedStatus[row[statusIdx]]; !ok {
+				continue
+			}
+		}
+
+		raw := row[originalIdx]
+		if _, ok := seen[raw]; ok {
+			continue
+		}
+		seen[raw] = struct{}{}
+
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		urls = append(urls, parsed)
+	}
+
+	return urls, nil
+}
+
+// CommonCrawlSeedProvider finds a domain's known URLs via the Common
+// Crawl index API.
+type CommonCrawlSeedProvider struct {
+	// Index is the Common Crawl crawl identifier, e.g. "CC-MAIN-2024-10".
+	Index  string
+	Domain string
+	// From and To restrict the capture date range, in the CDX index's
+	// yyyyMMddHHMMSS (or any left-prefix of it) form. Either may be left
+	// empty.
+	From, To string
+	// StatusFilter, if non-empty, restricts results to captures with one
+	// of these original HTTP status codes.
+	StatusFilter []int
+}
+
+// Seeds implements SeedProvider.
+func (p CommonCrawlSeedProvider) Seeds() ([]*url.URL, error) {
+	endpoint := fmt.Sprintf("https://index.commoncrawl.org/%s-index?url=%s&output=json",
+		p.Index, url.QueryEscape(p.Domain))
+	if p.From != "" {
+		endpoint += "&from=" + p.From
+	}
+	if p.To != "" {
+		endpoint += "&to=" + p.To
+	}
+
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	allowedStatus := make(map[string]struct{}, len(p.StatusFilter))
+	for _, code := range p.StatusFilter {
+		allowedStatus[strconv.Itoa(code)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{})
+	var urls []*url.URL
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var record struct {
+			URL    string `json:"url"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Error("error decoding Common Crawl record: ", err)
+			continue
+		}
+		if len(allowedStatus) > 0 {
+			if _, ok := allowedStatus[record.Status]; !ok {
+				continue
+			}
+		}
+		if _, ok := seen[record.URL]; ok {
+			continue
+		}
+		seen[record.URL] = struct{}{}
+
+		parsed, err := url.Parse(record.URL)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		urls = append(urls, parsed)
+	}
+
+	return urls, scanner.Err()
+}
+
+// mergeProviderSeeds runs every provider and appends any URL not
+// already present in urls.
+func mergeProviderSeeds(urls []string, providers []SeedProvider) []string {
+	seen := make(map[string]struct{}, len(urls))
+	for _, existing := range urls {
+		seen[existing] = struct{}{}
+	}
+
+	for _, provider := range providers {
+		discovered, err := provider.Seeds()
+		if err != nil {
+			log.Error("error collecting seeds: ", err)
+			continue
+		}
+
+		for _, u := range discovered {
+			str := u.String()
+			if _, ok := seen[str]; ok {
+				continue
+			}
+			seen[str] = struct{}{}
+			urls = append(urls, str)
+		}
+	}
+
+	return urls
+}
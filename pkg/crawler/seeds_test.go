@@ -0,0 +1,161 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// redirectTransport routes every request to targetHost regardless of
+// its original scheme/host, so tests can point the hardcoded
+// Wayback/Common Crawl endpoints at a local httptest.Server without
+// threading a base URL through the production providers. It forwards
+// the rewritten request to next rather than http.DefaultTransport,
+// since withRedirectedHTTP installs a redirectTransport as
+// http.DefaultTransport itself — routing back through that package
+// variable would recurse into RoundTrip forever.
+type redirectTransport struct {
+	targetHost string
+	next       http.RoundTripper
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = rt.targetHost
+	req.Host = rt.targetHost
+	return rt.next.RoundTrip(req)
+}
+
+// withRedirectedHTTP makes http.Get (and anything else using
+// http.DefaultTransport) hit server for the duration of the test.
+func withRedirectedHTTP(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	original := http.DefaultTransport
+	http.DefaultTransport = redirectTransport{
+		targetHost: strings.TrimPrefix(server.URL, "http://"),
+		next:       original,
+	}
+	t.Cleanup(func() { http.DefaultTransport = original })
+}
+
+func TestParseCDXRowsFiltersByStatusAndDedupes(t *testing.T) {
+	rows := [][]string{
+		{"original", "statuscode"},
+		{"https://example.com/a", "200"},
+		{"https://example.com/a", "200"},
+		{"https://example.com/b", "404"},
+	}
+
+	urls, err := parseCDXRows(rows, []int{200})
+	if err != nil {
+		t.Fatalf("parseCDXRows: %v", err)
+	}
+	if len(urls) != 1 || urls[0].String() != "https://example.com/a" {
+		t.Errorf("urls = %v, want just the deduplicated 200 status capture", urls)
+	}
+}
+
+func TestParseCDXRowsNoFilterReturnsEverything(t *testing.T) {
+	rows := [][]string{
+		{"original", "statuscode"},
+		{"https://example.com/a", "200"},
+		{"https://example.com/b", "404"},
+	}
+
+	urls, err := parseCDXRows(rows, nil)
+	if err != nil {
+		t.Fatalf("parseCDXRows: %v", err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("urls = %v, want both rows with no StatusFilter", urls)
+	}
+}
+
+func TestParseCDXRowsMissingOriginalColumn(t *testing.T) {
+	_, err := parseCDXRows([][]string{{"statuscode"}}, nil)
+	if err == nil {
+		t.Fatal("parseCDXRows() err = nil, want an error for a header row missing 'original'")
+	}
+}
+
+func TestParseCDXRowsEmpty(t *testing.T) {
+	urls, err := parseCDXRows(nil, nil)
+	if err != nil || urls != nil {
+		t.Errorf("parseCDXRows(nil) = %v, %v, want nil, nil", urls, err)
+	}
+}
+
+func TestCollectSitemapSeedsFollowsIndexWithinDepth(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/nested.xml</loc></sitemap></sitemapindex>`, server.URL)
+		case "/nested.xml":
+			fmt.Fprint(w, `<urlset><url><loc>https://example.com/page</loc></url></urlset>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	urls, err := collectSitemapSeeds(server.URL+"/index.xml", 1)
+	if err != nil {
+		t.Fatalf("collectSitemapSeeds: %v", err)
+	}
+	if len(urls) != 1 || urls[0].String() != "https://example.com/page" {
+		t.Errorf("urls = %v, want the one URL from the nested sitemap", urls)
+	}
+}
+
+func TestCollectSitemapSeedsStopsAtMaxIndexDepth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<sitemapindex><sitemap><loc>http://example.invalid/nested.xml</loc></sitemap></sitemapindex>`)
+	}))
+	defer server.Close()
+
+	urls, err := collectSitemapSeeds(server.URL, 0)
+	if err != nil {
+		t.Fatalf("collectSitemapSeeds: %v", err)
+	}
+	if len(urls) != 0 {
+		t.Errorf("urls = %v, want none (sitemap index nested deeper than MaxIndexDepth)", urls)
+	}
+}
+
+func TestCommonCrawlSeedProviderFiltersByStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{\"url\":\"https://example.com/a\",\"status\":\"200\"}\n{\"url\":\"https://example.com/b\",\"status\":\"404\"}\n")
+	}))
+	defer server.Close()
+	withRedirectedHTTP(t, server)
+
+	provider := CommonCrawlSeedProvider{Index: "CC-MAIN-2024-10", Domain: "example.com", StatusFilter: []int{200}}
+	urls, err := provider.Seeds()
+	if err != nil {
+		t.Fatalf("Seeds: %v", err)
+	}
+	if len(urls) != 1 || urls[0].String() != "https://example.com/a" {
+		t.Errorf("urls = %v, want just the 200 status record", urls)
+	}
+}
+
+func TestWaybackSeedProviderFiltersByStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[["original","statuscode"],["https://example.com/a","200"],["https://example.com/b","404"]]`)
+	}))
+	defer server.Close()
+	withRedirectedHTTP(t, server)
+
+	provider := WaybackSeedProvider{Domain: "example.com", StatusFilter: []int{200}}
+	urls, err := provider.Seeds()
+	if err != nil {
+		t.Fatalf("Seeds: %v", err)
+	}
+	if len(urls) != 1 || urls[0].String() != "https://example.com/a" {
+		t.Errorf("urls = %v, want just the 200 status capture", urls)
+	}
+}
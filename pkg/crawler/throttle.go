@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostThrottle serializes requests to the same host so a declared
+// Crawl-delay is honored, while leaving requests to other hosts free to
+// run concurrently.
+type hostThrottle struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+	last  map[string]time.Time
+}
+
+func newHostThrottle() *hostThrottle {
+	return &hostThrottle{
+		locks: make(map[string]*sync.Mutex),
+		last:  make(map[string]time.Time),
+	}
+}
+
+// wait blocks until delay has elapsed since the last request to
+// rawURL's host, then records this request as the new last one. It is a
+// no-op when delay is 0.
+func (h *hostThrottle) wait(rawURL string, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+
+	host := hostOf(rawURL)
+
+	lock := h.lockFor(host)
+	lock.Lock()
+	defer lock.Unlock()
+
+	h.mu.Lock()
+	last, seen := h.last[host]
+	h.mu.Unlock()
+
+	if seen {
+		if remaining := delay - time.Since(last); remaining > 0 {
+			time.Sleep(remaining)
+		}
+	}
+
+	h.mu.Lock()
+	h.last[host] = time.Now()
+	h.mu.Unlock()
+}
+
+// hostOf returns the host component of rawURL, or rawURL itself if it
+// fails to parse, so callers always have a usable map key.
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Host
+}
+
+func (h *hostThrottle) lockFor(host string) *sync.Mutex {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lock, ok := h.locks[host]
+	if !ok {
+		lock = &sync.Mutex{}
+		h.locks[host] = lock
+	}
+	return lock
+}
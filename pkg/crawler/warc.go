@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WARCConfig configures archival of crawled request/response pairs to a
+// WARC file, turning a crawl into a reusable archive instead of a
+// throwaway smoke test.
+type WARCConfig struct {
+	// OutputPath is the path of the WARC file to write. Archival is
+	// disabled when empty.
+	OutputPath string
+}
+
+// WARCWriter streams request/response pairs into a gzip-compressed,
+// multi-member WARC 1.1 file. Each record is written as its own gzip
+// member, per the WARC spec, so the file can be decompressed or indexed
+// record by record. It is safe for concurrent use by multiple
+// goroutines, since RunConcurrentGet fires many workers in parallel.
+type WARCWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewWARCWriter creates (or truncates) the file at path, returns a
+// WARCWriter that appends records to it, and writes the leading warcinfo
+// record describing the crawl.
+func NewWARCWriter(path string) (*WARCWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &WARCWriter{file: file}
+	if err := writer.writeInfoRecord(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Close closes the underlying WARC file.
+func (w *WARCWriter) Close() error {
+	return w.file.Close()
+}
+
+func (w *WARCWriter) writeInfoRecord() error {
+	body := []byte("software: crowlet\r\nformat: WARC File Format 1.1\r\n")
+	return w.writeRecord("warcinfo", "", "application/warc-fields", body)
+}
+
+// WriteExchange archives a single request/response pair as a WARC
+// "request" record followed by a WARC "response" record, both pointing
+// at targetURL. body is the already-buffered response body, so callers
+// that also need to parse the body (e.g. ExtractLinks) can reuse it
+// without a second read of resp.Body.
+func (w *WARCWriter) WriteExchange(targetURL string, req *http.Request, resp *http.Response, body []byte) error {
+	err := w.writeRecord("request", targetURL, "application/http; msgtype=request", buildRequestRecord(req))
+	if err != nil {
+		return err
+	}
+
+	return w.writeRecord("response", targetURL, "application/http; msgtype=response", buildResponseRecord(resp, body))
+}
+
+func buildRequestRecord(req *http.Request) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", req.URL.Host)
+	for key, values := range req.Header {
+		for _, value := range values {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func buildResponseRecord(resp *http.Response, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %s\r\n", resp.Status)
+	for key, values := range resp.Header {
+		for _, value := range values {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// writeRecord appends a single WARC record, header plus content, as its
+// own gzip member so the resulting file is a valid multi-member gzip
+// stream per the WARC/1.1 recommendation.
+func (w *WARCWriter) writeRecord(warcType, targetURI, contentType string, content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var header bytes.Buffer
+	header.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&header, "WARC-Type: %s\r\n", warcType)
+	fmt.Fprintf(&header, "WARC-Record-ID: <urn:uuid:%s>\r\n", uuid.New().String())
+	fmt.Fprintf(&header, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	if targetURI != "" {
+		fmt.Fprintf(&header, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	fmt.Fprintf(&header, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&header, "Content-Length: %d\r\n", len(content))
+	header.WriteString("\r\n")
+
+	gz := gzip.NewWriter(w.file)
+	if _, err := gz.Write(header.Bytes()); err != nil {
+		gz.Close()
+		return err
+	}
+	if _, err := gz.Write(content); err != nil {
+		gz.Close()
+		return err
+	}
+	if _, err := gz.Write([]byte("\r\n\r\n")); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWARCWriterWritesValidMultiMemberGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.warc.gz")
+
+	writer, err := NewWARCWriter(path)
+	if err != nil {
+		t.Fatalf("NewWARCWriter: %v", err)
+	}
+
+	targetURL, _ := url.Parse("https://example.com/page")
+	req, err := http.NewRequest("GET", targetURL.String(), nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	req.Header.Set("User-Agent", "crowlet-test")
+
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+	}
+
+	if err := writer.WriteExchange(targetURL.String(), req, resp, []byte("<html></html>")); err != nil {
+		t.Fatalf("WriteExchange: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	raw, err := readAll(path)
+	if err != nil {
+		t.Fatalf("reading WARC file: %v", err)
+	}
+
+	content := string(raw)
+
+	// warcinfo, request and response records should each be their own
+	// gzip member, decompressed transparently here as one continuous
+	// stream since gzip.Reader defaults to multistream mode.
+	for _, want := range []string{
+		"WARC-Type: warcinfo",
+		"WARC-Type: request",
+		"GET /page HTTP/1.1",
+		"WARC-Type: response",
+		"HTTP/1.1 200 OK",
+		"<html></html>",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("decompressed WARC content missing %q", want)
+		}
+	}
+
+	if strings.Index(content, "WARC-Type: request") > strings.Index(content, "WARC-Type: response") {
+		t.Errorf("request record should precede response record")
+	}
+}
+
+func readAll(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, gz); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,63 @@
+// Package metrics exposes Prometheus collectors for crowlet's crawl
+// activity, so a long crawl can be watched in Grafana instead of only
+// via end-of-run log output.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// RequestsTotal counts every issued request, by status code and
+	// target host; a request that failed without a response has status 0.
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crowlet_requests_total",
+		Help: "Total number of requests crowlet has issued, by status code and host.",
+	}, []string{"status", "host"})
+
+	// RequestDuration records how long each httpstat phase took.
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "crowlet_request_duration_seconds",
+		Help: "Request phase durations, as reported by httpstat.",
+	}, []string{"phase"})
+
+	// InflightRequests is the number of requests currently awaiting a
+	// response.
+	InflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "crowlet_inflight_requests",
+		Help: "Number of requests currently in flight.",
+	})
+
+	// QueueDepth is the number of URLs currently pending in the
+	// recursive crawl's frontier.
+	QueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "crowlet_queue_depth",
+		Help: "Number of URLs currently pending in the crawl frontier.",
+	})
+)
+
+// Serve starts an HTTP server exposing /metrics on addr. It blocks until
+// the server stops, so callers typically run it in its own goroutine.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, mux)
+}
+
+// ObserveRequest records the outcome of a single request: its phase
+// timings, and a count against its status code and host.
+func ObserveRequest(host string, statusCode int, dns, tcp, tls, server, content time.Duration) {
+	RequestsTotal.WithLabelValues(strconv.Itoa(statusCode), host).Inc()
+
+	RequestDuration.WithLabelValues("dns").Observe(dns.Seconds())
+	RequestDuration.WithLabelValues("tcp").Observe(tcp.Seconds())
+	RequestDuration.WithLabelValues("tls").Observe(tls.Seconds())
+	RequestDuration.WithLabelValues("server").Observe(server.Seconds())
+	RequestDuration.WithLabelValues("content").Observe(content.Seconds())
+}
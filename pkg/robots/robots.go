@@ -0,0 +1,238 @@
+// Package robots fetches and caches robots.txt per host, and answers
+// whether a given user agent may fetch a given URL.
+package robots
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// group holds the directives declared under one or more User-agent
+// lines in a robots.txt file.
+type group struct {
+	agents     []string
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// rules holds every parsed group for a single host, plus its host-wide
+// Sitemap: entries (Sitemap: isn't scoped to a group).
+type rules struct {
+	groups   []*group
+	sitemaps []string
+}
+
+// Cache fetches /robots.txt once per host and reuses the parsed result
+// for subsequent lookups against that host.
+type Cache struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	byHost map[string]*rules
+}
+
+// NewCache returns a Cache ready to serve Allowed and CrawlDelay lookups.
+func NewCache() *Cache {
+	return &Cache{
+		client: &http.Client{Timeout: 10 * time.Second},
+		byHost: make(map[string]*rules),
+	}
+}
+
+// Allowed reports whether userAgent may fetch rawURL, per the cached
+// robots.txt of rawURL's host. A rawURL that fails to parse, or a host
+// whose robots.txt can't be fetched, is treated as allowed.
+func (c *Cache) Allowed(userAgent, rawURL string) bool {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	return c.rulesFor(target).allowed(userAgent, target.Path)
+}
+
+// CrawlDelay returns the Crawl-delay declared for userAgent against
+// rawURL's host, or 0 if none was declared.
+func (c *Cache) CrawlDelay(userAgent, rawURL string) time.Duration {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+
+	group := c.rulesFor(target).matchGroup(userAgent)
+	if group == nil {
+		return 0
+	}
+	return group.crawlDelay
+}
+
+// Sitemaps returns the Sitemap: URLs declared in rawURL's host's
+// robots.txt, if any.
+func (c *Cache) Sitemaps(rawURL string) []string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	return c.rulesFor(target).sitemaps
+}
+
+func (c *Cache) rulesFor(target *url.URL) *rules {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.byHost[target.Host]; ok {
+		return cached
+	}
+
+	fetched := c.fetch(target)
+	c.byHost[target.Host] = fetched
+	return fetched
+}
+
+func (c *Cache) fetch(target *url.URL) *rules {
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+
+	resp, err := c.client.Get(robotsURL.String())
+	if err != nil {
+		log.Warn("error fetching robots.txt for ", target.Host, ": ", err)
+		return &rules{}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &rules{}
+	}
+
+	return parse(resp.Body)
+}
+
+// parse reads a robots.txt body and groups its directives by the
+// User-agent line(s) that precede them, plus the host-wide Sitemap:
+// entries. Consecutive User-agent lines belong to the same group, per
+// the de facto robots.txt standard.
+func parse(body io.Reader) *rules {
+	parsed := &rules{}
+
+	scanner := bufio.NewScanner(body)
+	var current *group
+	lastWasAgent := false
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			lastWasAgent = false
+			continue
+		}
+
+		field, value, ok := splitDirective(line)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(field) {
+		case "user-agent":
+			if !lastWasAgent || current == nil {
+				current = &group{}
+				parsed.groups = append(parsed.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+		case "disallow":
+			lastWasAgent = false
+			if current != nil && value != "" {
+				current.disallow = append(current.disallow, value)
+			}
+		case "allow":
+			lastWasAgent = false
+			if current != nil && value != "" {
+				current.allow = append(current.allow, value)
+			}
+		case "crawl-delay":
+			lastWasAgent = false
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			lastWasAgent = false
+			parsed.sitemaps = append(parsed.sitemaps, value)
+		}
+	}
+
+	return parsed
+}
+
+func splitDirective(line string) (field, value string, ok bool) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// matchGroup picks the group that applies to userAgent: the first group
+// whose User-agent token appears anywhere in userAgent (case-insensitively,
+// so "nosy-bot" matches "nosy-bot/2.0") wins over the wildcard "*" group,
+// which is used as a fallback when no group names userAgent specifically.
+func (r *rules) matchGroup(userAgent string) *group {
+	userAgent = strings.ToLower(userAgent)
+
+	var wildcard *group
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+				continue
+			}
+			if userAgent != "" && strings.Contains(userAgent, agent) {
+				return g
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// allowed applies the longest-match-wins rule used by the de facto
+// robots.txt standard: within the group that applies to userAgent, the
+// most specific (longest) matching Allow or Disallow prefix decides
+// whether path is fetchable. A host with no matching group at all
+// allows everything.
+func (r *rules) allowed(userAgent, path string) bool {
+	group := r.matchGroup(userAgent)
+	if group == nil {
+		return true
+	}
+
+	matchLen := -1
+	isAllowed := true
+
+	for _, prefix := range group.disallow {
+		if strings.HasPrefix(path, prefix) && len(prefix) > matchLen {
+			matchLen = len(prefix)
+			isAllowed = false
+		}
+	}
+	for _, prefix := range group.allow {
+		if strings.HasPrefix(path, prefix) && len(prefix) > matchLen {
+			matchLen = len(prefix)
+			isAllowed = true
+		}
+	}
+
+	return isAllowed
+}
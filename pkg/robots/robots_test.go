@@ -0,0 +1,77 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowedMatchesSpecificUserAgentGroup(t *testing.T) {
+	parsed := parse(strings.NewReader(`
+User-agent: *
+Disallow:
+
+User-agent: nosy-bot
+Disallow: /private/
+`))
+
+	if !parsed.allowed("generic-crawler/1.0", "/private/page") {
+		t.Errorf("allowed(generic-crawler, /private/page) = false, want true (only the wildcard group applies)")
+	}
+	if parsed.allowed("nosy-bot/2.0", "/private/page") {
+		t.Errorf("allowed(nosy-bot, /private/page) = true, want false (nosy-bot's own group disallows it)")
+	}
+	if !parsed.allowed("nosy-bot/2.0", "/public/page") {
+		t.Errorf("allowed(nosy-bot, /public/page) = false, want true")
+	}
+}
+
+func TestAllowedLongestPrefixWins(t *testing.T) {
+	parsed := parse(strings.NewReader(`
+User-agent: *
+Disallow: /a/
+Allow: /a/b/
+`))
+
+	if parsed.allowed("any", "/a/c/") != false {
+		t.Errorf("allowed(/a/c/) = true, want false (matches the shorter Disallow: /a/)")
+	}
+	if !parsed.allowed("any", "/a/b/d") {
+		t.Errorf("allowed(/a/b/d) = false, want true (matches the longer, more specific Allow: /a/b/)")
+	}
+}
+
+func TestMatchGroupFallsBackToWildcard(t *testing.T) {
+	parsed := parse(strings.NewReader(`
+User-agent: *
+Crawl-delay: 2
+
+User-agent: fast-bot
+Crawl-delay: 0
+`))
+
+	if g := parsed.matchGroup("fast-bot"); g == nil || g.crawlDelay.Seconds() != 0 {
+		t.Errorf("matchGroup(fast-bot) crawlDelay = %v, want 0", g)
+	}
+	if g := parsed.matchGroup("some-other-bot"); g == nil || g.crawlDelay.Seconds() != 2 {
+		t.Errorf("matchGroup(some-other-bot) crawlDelay = %v, want 2s (wildcard fallback)", g)
+	}
+}
+
+func TestParseSitemaps(t *testing.T) {
+	parsed := parse(strings.NewReader(`
+User-agent: *
+Disallow:
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/sitemap-news.xml
+`))
+
+	want := []string{"https://example.com/sitemap.xml", "https://example.com/sitemap-news.xml"}
+	if len(parsed.sitemaps) != len(want) {
+		t.Fatalf("sitemaps = %v, want %v", parsed.sitemaps, want)
+	}
+	for i, s := range want {
+		if parsed.sitemaps[i] != s {
+			t.Errorf("sitemaps[%d] = %q, want %q", i, parsed.sitemaps[i], s)
+		}
+	}
+}